@@ -0,0 +1,235 @@
+package identity
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cookieName is the name of the cookie that carries the signed identity payload
+const cookieName = "identity"
+
+// Keyring holds the keys used to sign (and, when enabled, encrypt) identity cookies.
+//
+// the first key is the primary key: it signs (and encrypts) every new cookie. every key in
+// the keyring is tried in turn when verifying (and decrypting) an existing cookie, which
+// allows a key to be rotated in without invalidating cookies issued under the previous one -
+// once every outstanding cookie has expired, the old key can be dropped from the keyring.
+type Keyring struct {
+	keys [][]byte
+}
+
+// NewKeyring builds a [Keyring] from a primary key and, optionally, one or more previous
+// keys kept around only to verify cookies that were signed before a rotation.
+func NewKeyring(primary []byte, rotated ...[]byte) Keyring {
+	return Keyring{keys: append([][]byte{primary}, rotated...)}
+}
+
+// identityPayload is the data signed (and optionally encrypted) into the identity cookie
+type identityPayload struct {
+	User   string `json:"user"`
+	Issued int64  `json:"iat"`
+	Expiry int64  `json:"exp"`
+	Nonce  string `json:"nonce"`
+}
+
+// CookieCodec encodes and signs the identity cookie payload, and verifies and decodes it again.
+type CookieCodec struct {
+	keyring Keyring
+	encrypt bool
+}
+
+// CodecOption configures a [CookieCodec]
+type CodecOption func(*CookieCodec)
+
+// WithEncryption additionally encrypts the cookie payload with AES-GCM, using the same
+// keyring; by default the payload is signed but left in the clear.
+func WithEncryption() CodecOption {
+	return func(c *CookieCodec) { c.encrypt = true }
+}
+
+// NewCookieCodec builds a [CookieCodec] from a keyring and a set of [CodecOption]s
+func NewCookieCodec(keyring Keyring, opts ...CodecOption) *CookieCodec {
+	c := &CookieCodec{keyring: keyring}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetOptions controls the cookie attributes used by [CookieCodec.SetUser]
+type SetOptions struct {
+	TTL      time.Duration
+	Secure   bool
+	SameSite http.SameSite
+	Path     string
+}
+
+// DefaultSetOptions returns the attributes a login flow should use unless it has a reason not to:
+// a 24h TTL, Secure, SameSite=Lax and scoped to the whole site.
+func DefaultSetOptions() SetOptions {
+	return SetOptions{
+		TTL:      24 * time.Hour,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	}
+}
+
+// SetUser encodes and signs user into the identity cookie and writes it to rw, using opts to
+// control its lifetime and attributes. The cookie is always HttpOnly.
+func (c *CookieCodec) SetUser(rw http.ResponseWriter, user string, opts SetOptions) error {
+	value, err := c.encode(user, opts.TTL)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(rw, &http.Cookie{
+		Name:     cookieName,
+		Value:    value,
+		Path:     opts.Path,
+		MaxAge:   int(opts.TTL.Seconds()),
+		HttpOnly: true,
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
+	})
+	return nil
+}
+
+// extractUser reads, verifies and decodes the identity cookie from req, rejecting it if the
+// signature doesn't check out under any key in the keyring or if it has expired.
+func (c *CookieCodec) extractUser(req *http.Request) (string, error) {
+	cookie, err := req.Cookie(cookieName)
+	if err != nil {
+		return "", err
+	}
+	p, err := c.decode(cookie.Value)
+	if err != nil {
+		return "", err
+	}
+	return p.User, nil
+}
+
+// encode builds, signs (and optionally encrypts) the payload for user, returning the cookie value
+func (c *CookieCodec) encode(user string, ttl time.Duration) (string, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	raw, err := json.Marshal(identityPayload{
+		User:   user,
+		Issued: now.Unix(),
+		Expiry: now.Add(ttl).Unix(),
+		Nonce:  hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	key := c.keyring.keys[0]
+	if c.encrypt {
+		raw, err = encryptAESGCM(deriveKey(key), raw)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(raw)
+
+	return base64.RawURLEncoding.EncodeToString(raw) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// decode verifies the signature on value against every key in the keyring, decrypts it if
+// needed, and rejects the payload if it has expired.
+func (c *CookieCodec) decode(value string) (identityPayload, error) {
+	parts := strings.SplitN(value, ".", 2)
+	if len(parts) != 2 {
+		return identityPayload{}, errors.New("identity: malformed cookie")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return identityPayload{}, errors.New("identity: malformed cookie")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return identityPayload{}, errors.New("identity: malformed cookie")
+	}
+
+	var verifyKey []byte
+	for _, key := range c.keyring.keys {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(raw)
+		if hmac.Equal(mac.Sum(nil), sig) {
+			verifyKey = key
+			break
+		}
+	}
+	if verifyKey == nil {
+		return identityPayload{}, errors.New("identity: invalid signature")
+	}
+
+	if c.encrypt {
+		raw, err = decryptAESGCM(deriveKey(verifyKey), raw)
+		if err != nil {
+			return identityPayload{}, err
+		}
+	}
+
+	var p identityPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return identityPayload{}, errors.New("identity: malformed cookie")
+	}
+	if time.Now().Unix() > p.Expiry {
+		return identityPayload{}, errors.New("identity: cookie expired")
+	}
+	return p, nil
+}
+
+// deriveKey stretches an arbitrary-length signing key into the 32 bytes AES-256-GCM needs
+func deriveKey(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:]
+}
+
+func encryptAESGCM(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func decryptAESGCM(key, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("identity: ciphertext too short")
+	}
+	nonce, ct := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ct, nil)
+}
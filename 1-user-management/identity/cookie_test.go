@@ -0,0 +1,132 @@
+package identity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cookiesFrom(rec *httptest.ResponseRecorder) []*http.Cookie {
+	return rec.Result().Cookies()
+}
+
+func requestWithCookies(cookies []*http.Cookie) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+func TestCookieCodec_SetUserExtractUserRoundTrip(t *testing.T) {
+	c := NewCookieCodec(NewKeyring([]byte("primary-signing-key")))
+
+	rec := httptest.NewRecorder()
+	if err := c.SetUser(rec, "alice", DefaultSetOptions()); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+
+	user, err := c.extractUser(requestWithCookies(cookiesFrom(rec)))
+	if err != nil {
+		t.Fatalf("extractUser: %v", err)
+	}
+	if user != "alice" {
+		t.Fatalf("user = %q, want %q", user, "alice")
+	}
+}
+
+func TestCookieCodec_TamperedCookieRejected(t *testing.T) {
+	c := NewCookieCodec(NewKeyring([]byte("primary-signing-key")))
+
+	rec := httptest.NewRecorder()
+	if err := c.SetUser(rec, "alice", DefaultSetOptions()); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+
+	tampered := *cookiesFrom(rec)[0]
+	dot := strings.IndexByte(tampered.Value, '.')
+	if dot < 0 {
+		t.Fatalf("cookie value has no '.' separator: %q", tampered.Value)
+	}
+	payload := []byte(tampered.Value[:dot])
+	last := len(payload) - 1
+	if payload[last] == 'A' {
+		payload[last] = 'B'
+	} else {
+		payload[last] = 'A'
+	}
+	tampered.Value = string(payload) + tampered.Value[dot:]
+
+	if _, err := c.extractUser(requestWithCookies([]*http.Cookie{&tampered})); err == nil {
+		t.Fatal("extractUser: expected an error for a tampered cookie, got nil")
+	}
+}
+
+func TestCookieCodec_ExpiredCookieRejectedWith401(t *testing.T) {
+	c := NewCookieCodec(NewKeyring([]byte("primary-signing-key")))
+
+	rec := httptest.NewRecorder()
+	opts := SetOptions{TTL: -time.Second, Path: "/"}
+	if err := c.SetUser(rec, "alice", opts); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+
+	h := Middleware(WithCodec(c))(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not run for an expired cookie")
+	}))
+
+	out := httptest.NewRecorder()
+	h.ServeHTTP(out, requestWithCookies(cookiesFrom(rec)))
+
+	if out.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", out.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestCookieCodec_WithEncryptionRoundTrip(t *testing.T) {
+	c := NewCookieCodec(NewKeyring([]byte("primary-signing-key")), WithEncryption())
+
+	rec := httptest.NewRecorder()
+	if err := c.SetUser(rec, "alice", DefaultSetOptions()); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+
+	user, err := c.extractUser(requestWithCookies(cookiesFrom(rec)))
+	if err != nil {
+		t.Fatalf("extractUser: %v", err)
+	}
+	if user != "alice" {
+		t.Fatalf("user = %q, want %q", user, "alice")
+	}
+}
+
+func TestCookieCodec_KeyRotationVerifiesOldKeyWhileNewCookiesSignWithPrimary(t *testing.T) {
+	oldKey := []byte("old-signing-key")
+	oldCodec := NewCookieCodec(NewKeyring(oldKey))
+
+	rec := httptest.NewRecorder()
+	if err := oldCodec.SetUser(rec, "alice", DefaultSetOptions()); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+	cookieFromBeforeRotation := cookiesFrom(rec)
+
+	rotated := NewCookieCodec(NewKeyring([]byte("new-signing-key"), oldKey))
+
+	user, err := rotated.extractUser(requestWithCookies(cookieFromBeforeRotation))
+	if err != nil {
+		t.Fatalf("extractUser of a cookie signed under the rotated-out key: %v", err)
+	}
+	if user != "alice" {
+		t.Fatalf("user = %q, want %q", user, "alice")
+	}
+
+	rec2 := httptest.NewRecorder()
+	if err := rotated.SetUser(rec2, "bob", DefaultSetOptions()); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+	if _, err := oldCodec.extractUser(requestWithCookies(cookiesFrom(rec2))); err == nil {
+		t.Fatal("expected the old-key-only codec to reject a cookie signed under the new primary key")
+	}
+}
@@ -3,6 +3,7 @@ package identity
 import (
 	"context"
 	"net/http"
+	"time"
 )
 
 // Two patterns are used to guarantee that a key is unique and comparable
@@ -45,56 +46,115 @@ const (
 	key
 )
 
-// NOTE: the name of the function that creates a context should start with "ContextWith"
+// ContextWithUser is a thin shim over [ContextWithPrincipal] kept for backward compatibility
+// with code that only ever has a bare user name, not a hydrated [Principal]; mirrors how
+// [UserFromContext] was turned into a shim over [PrincipalFromContext].
 func ContextWithUser(ctx context.Context, user string) context.Context {
-	return context.WithValue(ctx, key, user)
+	return ContextWithPrincipal(ctx, Principal{User: user})
 }
 
-// NOTE: the name of the function that returns the value from the context should have a name that ends with "FromContext"
+// UserFromContext is a thin shim over [PrincipalFromContext] kept for backward compatibility
+// with code that only ever cared about the user name, not their roles/scopes
 func UserFromContext(ctx context.Context) (string, bool) {
-	user, ok := ctx.Value(key).(string)
-	return user, ok
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return p.User, true
 }
 
-// a real implementation would be signed to make sure
-// the identity didn't spoof their identity
-func extractUser(req *http.Request) (string, error) {
-	userCookie, err := req.Cookie("identity")
-	if err != nil {
-		return "", err
-	}
-	return userCookie.Value, nil
+// middlewareConfig holds what [Middleware] and [RefreshMiddleware] need to verify / re-issue
+// the identity cookie, and to hydrate the [Principal] that replaces the bare user name
+type middlewareConfig struct {
+	codec  *CookieCodec
+	loader PrincipalLoader
+}
+
+// MiddlewareOption configures [Middleware]
+type MiddlewareOption func(*middlewareConfig)
+
+// WithCodec plugs the [CookieCodec] that [Middleware] uses to verify the identity cookie; it
+// is required, there is no insecure fallback.
+func WithCodec(codec *CookieCodec) MiddlewareOption {
+	return func(cfg *middlewareConfig) { cfg.codec = codec }
+}
+
+// WithPrincipalLoader plugs a [PrincipalLoader] that [Middleware] uses to hydrate the
+// [Principal]'s roles, scopes and attributes after the identity cookie has been verified. If
+// omitted, the [Principal] only ever carries the bare user name.
+func WithPrincipalLoader(loader PrincipalLoader) MiddlewareOption {
+	return func(cfg *middlewareConfig) { cfg.loader = loader }
 }
 
 // Middleware defines how user information is loaded / managed
-func Middleware(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-		user, err := extractUser(req)
-		if err != nil {
-			rw.WriteHeader(http.StatusUnauthorized)
-			rw.Write([]byte("unauthorized"))
-			return
-		}
-
-		ctx := req.Context()
-		// creates a new context that contains the user
-		ctx = ContextWithUser(ctx, user)
-		// creates a new request with the old request and the context containing the user
-		req = req.WithContext(ctx)
-		h.ServeHTTP(rw, req)
-	})
+//
+// it verifies the signed (and, depending on the [CookieCodec], encrypted) identity cookie set
+// by [CookieCodec.SetUser], rejecting missing, tampered-with or expired cookies with a 401,
+// then hydrates a [Principal] via the configured [PrincipalLoader] and attaches it to the
+// request context for [PrincipalFromContext] (and, for backward compatibility, [UserFromContext])
+func Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	var cfg middlewareConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if cfg.codec == nil {
+				rw.WriteHeader(http.StatusInternalServerError)
+				rw.Write([]byte("identity: no codec configured, see identity.WithCodec"))
+				return
+			}
+
+			user, err := cfg.codec.extractUser(req)
+			if err != nil {
+				rw.WriteHeader(http.StatusUnauthorized)
+				rw.Write([]byte("unauthorized"))
+				return
+			}
+
+			principal := Principal{User: user}
+			if cfg.loader != nil {
+				principal, err = cfg.loader.Load(req.Context(), user)
+				if err != nil {
+					rw.WriteHeader(http.StatusInternalServerError)
+					rw.Write([]byte("identity: loading principal: " + err.Error()))
+					return
+				}
+			}
+
+			ctx := req.Context()
+			// creates a new context that contains the principal
+			ctx = ContextWithPrincipal(ctx, principal)
+			// creates a new request with the old request and the context containing the principal
+			req = req.WithContext(ctx)
+			h.ServeHTTP(rw, req)
+		})
+	}
 }
 
-func SetUser(user string, rw http.ResponseWriter) {
-	http.SetCookie(rw, &http.Cookie{
-		Name:  "identity",
-		Value: user,
-	})
+// RefreshMiddleware re-issues the identity cookie, with a fresh TTL, whenever the incoming
+// cookie is valid but within refreshWindow of expiring - so a continuously active session
+// never hits the hard expiry set by [CookieCodec.SetUser]. It must run "inside" [Middleware],
+// i.e. after the user has already been authenticated.
+func RefreshMiddleware(codec *CookieCodec, refreshWindow time.Duration, opts SetOptions) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if cookie, err := req.Cookie(cookieName); err == nil {
+				if payload, err := codec.decode(cookie.Value); err == nil {
+					if remaining := time.Until(time.Unix(payload.Expiry, 0)); remaining > 0 && remaining <= refreshWindow {
+						_ = codec.SetUser(rw, payload.User, opts)
+					}
+				}
+			}
+			h.ServeHTTP(rw, req)
+		})
+	}
 }
 
 func DeleteUser(rw http.ResponseWriter) {
 	http.SetCookie(rw, &http.Cookie{
-		Name:   "identity",
+		Name:   cookieName,
 		Value:  "",
 		Path:   "/",
 		MaxAge: -1,
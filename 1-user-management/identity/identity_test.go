@@ -0,0 +1,18 @@
+package identity
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextWithUser_RoundTripsThroughUserFromContext(t *testing.T) {
+	ctx := ContextWithUser(context.Background(), "bob")
+
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		t.Fatal("UserFromContext: ok = false, want true")
+	}
+	if user != "bob" {
+		t.Fatalf("UserFromContext: user = %q, want %q", user, "bob")
+	}
+}
@@ -0,0 +1,128 @@
+package identity
+
+import (
+	"context"
+	"net/http"
+)
+
+// Principal is the authenticated identity and its authorization data, as hydrated by a
+// [PrincipalLoader] once [Middleware] has verified the identity cookie.
+type Principal struct {
+	User       string
+	Roles      []string
+	Scopes     []string
+	Attributes map[string]string
+}
+
+// HasRole reports whether p has been granted role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether p has been granted scope
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Principal) hasAnyRole(roles []string) bool {
+	for _, role := range roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Principal) hasAnyScope(scopes []string) bool {
+	for _, scope := range scopes {
+		if p.HasScope(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// principalKey is an unexported key-type for writing and reading a [Principal] into a [context.Context]
+type principalKey struct{}
+
+// ContextWithPrincipal is an API to add a [Principal] to a [context.Context]
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext is an API to read a [Principal] from a [context.Context] instance
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// PrincipalLoader hydrates a [Principal]'s roles, scopes and attributes for an authenticated
+// user, once [Middleware] has verified the identity cookie - typically by looking the user up
+// in a database or by parsing claims out of a token.
+type PrincipalLoader interface {
+	Load(ctx context.Context, user string) (Principal, error)
+}
+
+// RequireRole returns a middleware that responds 403 unless the request's [Principal] has at
+// least one of the given roles. It must run after [Middleware].
+func RequireRole(role ...string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			principal, ok := PrincipalFromContext(req.Context())
+			if !ok || !principal.hasAnyRole(role) {
+				rw.WriteHeader(http.StatusForbidden)
+				rw.Write([]byte("forbidden"))
+				return
+			}
+			h.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// RequireScope returns a middleware that responds 403 unless the request's [Principal] has at
+// least one of the given scopes. It must run after [Middleware].
+func RequireScope(scope ...string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			principal, ok := PrincipalFromContext(req.Context())
+			if !ok || !principal.hasAnyScope(scope) {
+				rw.WriteHeader(http.StatusForbidden)
+				rw.Write([]byte("forbidden"))
+				return
+			}
+			h.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// Authorize returns a middleware that runs policy against the request's [Principal],
+// responding 403 if it returns an error, for authorization rules that don't fit the simple
+// role/scope shape of [RequireRole] / [RequireScope]. It must run after [Middleware].
+func Authorize(policy func(Principal, *http.Request) error) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			principal, ok := PrincipalFromContext(req.Context())
+			if !ok {
+				rw.WriteHeader(http.StatusForbidden)
+				rw.Write([]byte("forbidden"))
+				return
+			}
+			if err := policy(principal, req); err != nil {
+				rw.WriteHeader(http.StatusForbidden)
+				rw.Write([]byte(err.Error()))
+				return
+			}
+			h.ServeHTTP(rw, req)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func requestWithPrincipal(p Principal, ok bool) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if !ok {
+		return req
+	}
+	return req.WithContext(ContextWithPrincipal(context.Background(), p))
+}
+
+func forbiddenHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not run")
+	})
+}
+
+// assertPassesThrough wraps a marker handler with mw and asserts it actually ran, i.e. mw let
+// the request through to it.
+func assertPassesThrough(t *testing.T, mw func(http.Handler) http.Handler, req *http.Request) {
+	t.Helper()
+	var ran bool
+	h := mw(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		ran = true
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !ran {
+		t.Fatal("expected the wrapped handler to run")
+	}
+}
+
+func assertForbidden(t *testing.T, mw func(http.Handler) http.Handler, req *http.Request) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mw(forbiddenHandler(t)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	mw := RequireRole("admin")
+
+	t.Run("forbids a missing principal", func(t *testing.T) {
+		assertForbidden(t, mw, requestWithPrincipal(Principal{}, false))
+	})
+
+	t.Run("forbids a principal without the role", func(t *testing.T) {
+		req := requestWithPrincipal(Principal{User: "bob", Roles: []string{"viewer"}}, true)
+		assertForbidden(t, mw, req)
+	})
+
+	t.Run("passes through a principal with the role", func(t *testing.T) {
+		req := requestWithPrincipal(Principal{User: "admin", Roles: []string{"admin"}}, true)
+		assertPassesThrough(t, mw, req)
+	})
+}
+
+func TestRequireScope(t *testing.T) {
+	mw := RequireScope("read:reports")
+
+	t.Run("forbids a missing principal", func(t *testing.T) {
+		assertForbidden(t, mw, requestWithPrincipal(Principal{}, false))
+	})
+
+	t.Run("forbids a principal without the scope", func(t *testing.T) {
+		req := requestWithPrincipal(Principal{User: "bob", Scopes: []string{"write:reports"}}, true)
+		assertForbidden(t, mw, req)
+	})
+
+	t.Run("passes through a principal with the scope", func(t *testing.T) {
+		req := requestWithPrincipal(Principal{User: "bob", Scopes: []string{"read:reports"}}, true)
+		assertPassesThrough(t, mw, req)
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	allow := func(Principal, *http.Request) error { return nil }
+	deny := func(Principal, *http.Request) error { return errors.New("nope") }
+
+	t.Run("forbids a missing principal", func(t *testing.T) {
+		assertForbidden(t, Authorize(allow), requestWithPrincipal(Principal{}, false))
+	})
+
+	t.Run("forbids when the policy returns an error", func(t *testing.T) {
+		req := requestWithPrincipal(Principal{User: "bob"}, true)
+		assertForbidden(t, Authorize(deny), req)
+	})
+
+	t.Run("passes through when the policy allows", func(t *testing.T) {
+		req := requestWithPrincipal(Principal{User: "bob"}, true)
+		assertPassesThrough(t, Authorize(allow), req)
+	})
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"log"
 	"net/http"
+	"serverkit"
 	"strings"
+	"time"
 	"user-management/identity"
 )
 
@@ -15,6 +18,7 @@ type Logic interface {
 }
 type Controller struct {
 	Logic Logic
+	Codec *identity.CookieCodec
 }
 
 // Login implements the worst authentication system known.
@@ -25,7 +29,11 @@ func (c Controller) Login(rw http.ResponseWriter, req *http.Request) {
 		rw.Write([]byte("No user specified"))
 		return
 	}
-	identity.SetUser(userName, rw)
+	if err := c.Codec.SetUser(rw, userName, identity.DefaultSetOptions()); err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(err.Error()))
+		return
+	}
 	rw.WriteHeader(http.StatusOK)
 	rw.Write([]byte("user logged in"))
 }
@@ -72,23 +80,51 @@ func (c Controller) Logout(rw http.ResponseWriter, r *http.Request) {
 	rw.Write([]byte("user logged out"))
 }
 
+// Admin is gated by [identity.RequireRole] to demonstrate role-based authorization on top of identity.Middleware
+func (c Controller) Admin(rw http.ResponseWriter, req *http.Request) {
+	rw.WriteHeader(http.StatusOK)
+	rw.Write([]byte("welcome, admin"))
+}
+
 type LogicImpl struct{}
 
 func (l LogicImpl) BusinessLogic(ctx context.Context, user string, data string) (string, error) {
 	return fmt.Sprintf("Hello %s, thank you for sending me %s", user, data), nil
 }
 
+// adminPrincipalLoader is a trivial [identity.PrincipalLoader] standing in for a real store
+// (database, JWT claims, ...); it grants the "admin" role to the single user named "admin".
+type adminPrincipalLoader struct{}
+
+func (adminPrincipalLoader) Load(_ context.Context, user string) (identity.Principal, error) {
+	p := identity.Principal{User: user}
+	if user == "admin" {
+		p.Roles = []string{"admin"}
+	}
+	return p, nil
+}
+
 func main() {
+	codec := identity.NewCookieCodec(identity.NewKeyring([]byte("dev-signing-key-change-me")))
+
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
+	r.Use(serverkit.DeadlineBudget)
+	r.Use(serverkit.Timeout(5 * time.Second))
 	controller := Controller{
 		Logic: LogicImpl{},
+		Codec: codec,
 	}
 	r.Get("/login", controller.Login)
 	r.Route("/business", func(r chi.Router) {
-		r = r.With(identity.Middleware)
+		r = r.With(identity.Middleware(identity.WithCodec(codec), identity.WithPrincipalLoader(adminPrincipalLoader{})))
 		r.Get("/", controller.DoLogic)
 		r.Get("/logout", controller.Logout)
+		r.With(identity.RequireRole("admin")).Get("/admin", controller.Admin)
 	})
-	http.ListenAndServe(":3000", r)
+
+	srv := &http.Server{Addr: ":3000", Handler: r}
+	if err := serverkit.Run(context.Background(), srv, 10*time.Second); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -2,10 +2,15 @@ package main
 
 import (
 	"application-management/tracker"
+	"application-management/tracker/httpclient"
 	"context"
 	"github.com/go-chi/chi/v5"
 	"io"
+	"log"
+	"logctx"
 	"net/http"
+	"serverkit"
+	"time"
 )
 
 type Logic interface {
@@ -27,36 +32,38 @@ func (c Controller) First(rw http.ResponseWriter, req *http.Request) {
 	rw.Write([]byte(result))
 }
 
-// Logger offers an interface that implicitly matches [tracker.Logger.Log]
-type Logger interface {
-	Log(context.Context, string)
+// HTTPDoer implicitly matches both [*http.Client] and [*httpclient.Client], letting LogicImpl
+// depend on either without knowing which one main() wired up
+type HTTPDoer interface {
+	Do(*http.Request) (*http.Response, error)
 }
 
-// RequestDecorator implicitly matches [tracker.Request] and allows to wire up a dependency that only main() is aware of
-type RequestDecorator func(*http.Request) *http.Request
-
 type LogicImpl struct {
-	RequestDecorator RequestDecorator
-	Logger           Logger
-	Remote           string
+	HTTPClient HTTPDoer
+	Remote     string
 }
 
 func (l LogicImpl) Process(ctx context.Context, data string) (string, error) {
-	l.Logger.Log(ctx, "starting Process with "+data)
+	logctx.From(ctx).Info("starting Process with " + data)
+
+	// opens a child span around the outbound call to "second" and logs its duration once it returns
+	ctx, endSpan := tracker.StartSpan(ctx, "call-second")
+	defer endSpan()
+
 	req, err := http.NewRequestWithContext(ctx,
 		http.MethodGet, l.Remote+"/second?query="+data, nil)
 	if err != nil {
-		l.Logger.Log(ctx, "error building remote request:"+err.Error())
+		logctx.From(ctx).Error("error building remote request: " + err.Error())
 		return "", err
 	}
-	req = l.RequestDecorator(req)
-	resp, err := http.DefaultClient.Do(req)
+	// l.HTTPClient propagates the GUID/trace headers itself (see [tracker.Request]), and retries / circuit-breaks the call
+	resp, err := l.HTTPClient.Do(req)
 	if err != nil {
-		l.Logger.Log(ctx, "error building remote request:"+err.Error())
+		logctx.From(ctx).Error("error calling second: " + err.Error())
 		return "", err
 	}
 	if resp.Body == nil {
-		l.Logger.Log(ctx, "empty response from second")
+		logctx.From(ctx).Info("empty response from second")
 		return "", nil
 	}
 	defer resp.Body.Close()
@@ -67,10 +74,27 @@ func (l LogicImpl) Process(ctx context.Context, data string) (string, error) {
 func main() {
 	r := chi.NewRouter()
 	r.Use(tracker.Middleware)
+	r.Use(logctx.Middleware(
+		logctx.WithExtractor(func(req *http.Request) (string, string, bool) {
+			guid, ok := tracker.GUIDFromContext(req.Context())
+			return "guid", guid, ok
+		}),
+		logctx.WithExtractor(func(req *http.Request) (string, string, bool) {
+			tc, ok := tracker.TraceFromContext(req.Context())
+			return "trace_id", tc.TraceID, ok
+		}),
+		logctx.WithExtractor(func(req *http.Request) (string, string, bool) {
+			tc, ok := tracker.TraceFromContext(req.Context())
+			return "span_id", tc.SpanID, ok
+		}),
+	))
+	r.Use(serverkit.DeadlineBudget)
+	r.Use(serverkit.Timeout(5 * time.Second))
 	controller := Controller{
 
 		// NOTE:
-		// the GUID is passed through to the logger and request decorator without the business logic being aware of it,
+		// the GUID/trace/retry/circuit-breaking concerns are all handled inside httpclient.New()
+		// without the business logic being aware of any of it,
 		//
 		// SEPARATING
 		// (1) the DATA needed FOR PROGRAM LOGIC
@@ -79,11 +103,14 @@ func main() {
 		//
 		// the only place that's aware of the association is the code below that wires up the dependencies
 		Logic: LogicImpl{
-			RequestDecorator: tracker.Request,
-			Logger:           tracker.Logger{},
-			Remote:           "http://localhost:4000",
+			HTTPClient: httpclient.New(),
+			Remote:     "http://localhost:4000",
 		},
 	}
 	r.Get("/first", controller.First)
-	http.ListenAndServe(":3000", r)
+
+	srv := &http.Server{Addr: ":3000", Handler: r}
+	if err := serverkit.Run(context.Background(), srv, 10*time.Second); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,99 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// minSamples is how many outcomes a closed breaker waits for before its failure ratio means anything
+const minSamples = 5
+
+type breakerState int
+
+const (
+	closed breakerState = iota
+	open
+	halfOpen
+)
+
+// breaker is a closed/open/half-open circuit breaker scoped to a single downstream host.
+//
+// closed lets every request through and trips to open once at least minSamples requests have
+// been observed and the failure ratio reaches failureRatio. open rejects every request until
+// cooldown has elapsed, then allows a single half-open probe through: success resets to
+// closed, failure trips back to open.
+type breaker struct {
+	mu           sync.Mutex
+	state        breakerState
+	failureRatio float64
+	cooldown     time.Duration
+	openedAt     time.Time
+	successes    int
+	failures     int
+	probing      bool
+}
+
+func newBreaker(failureRatio float64, cooldown time.Duration) *breaker {
+	return &breaker{failureRatio: failureRatio, cooldown: cooldown}
+}
+
+// allow reports whether a request may proceed, transitioning open -> half-open once cooldown
+// has elapsed. Only the single caller that claims the half-open probe gets true; every other
+// caller is rejected until that probe's outcome is recorded.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == open {
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = halfOpen
+	}
+	if b.state == halfOpen {
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+func (b *breaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == halfOpen {
+		b.reset()
+		return
+	}
+	b.successes++
+}
+
+func (b *breaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == halfOpen {
+		b.trip()
+		return
+	}
+
+	b.failures++
+	if total := b.successes + b.failures; total >= minSamples && float64(b.failures)/float64(total) >= b.failureRatio {
+		b.trip()
+	}
+}
+
+func (b *breaker) trip() {
+	b.state = open
+	b.openedAt = time.Now()
+	b.successes, b.failures = 0, 0
+	b.probing = false
+}
+
+func (b *breaker) reset() {
+	b.state = closed
+	b.successes, b.failures = 0, 0
+	b.probing = false
+}
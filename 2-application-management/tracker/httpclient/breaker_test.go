@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBreaker_HalfOpenAllowsOnlyOneProbe(t *testing.T) {
+	b := newBreaker(0.5, time.Millisecond)
+	b.trip()
+	time.Sleep(2 * time.Millisecond) // let cooldown elapse so the next allow() enters half-open
+
+	const callers = 20
+	var wg sync.WaitGroup
+	var allowed int32
+	var mu sync.Mutex
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			if b.allow() {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 1 {
+		t.Fatalf("allowed = %d concurrent half-open probes, want 1", allowed)
+	}
+}
+
+func TestBreaker_HalfOpenFailureRetripsAndAllowsAnotherProbeAfterCooldown(t *testing.T) {
+	b := newBreaker(0.5, time.Millisecond)
+	b.trip()
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("expected the first half-open probe to be allowed")
+	}
+	if b.allow() {
+		t.Fatal("expected a second concurrent probe to be rejected")
+	}
+
+	b.recordFailure()
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("expected a fresh probe to be allowed after the breaker re-tripped and cooled down again")
+	}
+}
+
+func TestBreaker_HalfOpenSuccessCloses(t *testing.T) {
+	b := newBreaker(0.5, time.Millisecond)
+	b.trip()
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("expected the half-open probe to be allowed")
+	}
+	b.recordSuccess()
+
+	if b.state != closed {
+		t.Fatalf("state = %v, want closed", b.state)
+	}
+	if !b.allow() {
+		t.Fatal("expected closed breaker to allow requests")
+	}
+}
@@ -0,0 +1,230 @@
+// Package httpclient wraps an [http.Client] with tracker's GUID/trace propagation, retries
+// with exponential backoff and jitter, and a per-host circuit breaker, so callers like
+// LogicImpl don't have to reimplement resilience around every outbound call themselves.
+package httpclient
+
+import (
+	"application-management/tracker"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Recorder observes the outcome of each attempt a [Client] makes, e.g. to export it as a
+// Prometheus metric. d is the attempt's latency; statusCode is 0 if err is non-nil and no
+// response was received.
+type Recorder interface {
+	Record(host string, attempt int, d time.Duration, statusCode int, err error)
+}
+
+// noopRecorder is used when no [Recorder] is configured
+type noopRecorder struct{}
+
+func (noopRecorder) Record(string, int, time.Duration, int, error) {}
+
+// idempotentMethods are retried on failure; everything else (POST, PATCH, ...) is attempted once only
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// Option configures a [Client]
+type Option func(*Client)
+
+// WithMaxAttempts overrides the default of 3 attempts for idempotent methods
+func WithMaxAttempts(n int) Option {
+	return func(c *Client) { c.maxAttempts = n }
+}
+
+// WithBackoff overrides the default 100ms base / 2s max exponential backoff
+func WithBackoff(base, max time.Duration) Option {
+	return func(c *Client) { c.baseDelay, c.maxDelay = base, max }
+}
+
+// WithBreaker overrides the default circuit breaker of a 50% failure ratio tripping a 10s cooldown
+func WithBreaker(failureRatio float64, cooldown time.Duration) Option {
+	return func(c *Client) { c.failureRatio, c.cooldown = failureRatio, cooldown }
+}
+
+// WithRecorder plugs a [Recorder] that observes every attempt, e.g. a Prometheus adapter
+func WithRecorder(r Recorder) Option {
+	return func(c *Client) { c.recorder = r }
+}
+
+// WithHTTPClient overrides the underlying [*http.Client]; defaults to [http.DefaultClient]
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// Client wraps an [*http.Client], propagating tracker's GUID/trace headers on every request,
+// retrying idempotent methods with exponential backoff and jitter, and protecting each
+// downstream host behind its own circuit [breaker].
+type Client struct {
+	httpClient   *http.Client
+	maxAttempts  int
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+	failureRatio float64
+	cooldown     time.Duration
+	recorder     Recorder
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// New builds a [Client] with sane defaults, overridden by opts.
+func New(opts ...Option) *Client {
+	c := &Client{
+		httpClient:   http.DefaultClient,
+		maxAttempts:  3,
+		baseDelay:    100 * time.Millisecond,
+		maxDelay:     2 * time.Second,
+		failureRatio: 0.5,
+		cooldown:     10 * time.Second,
+		recorder:     noopRecorder{},
+		breakers:     make(map[string]*breaker),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) breakerFor(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[host]
+	if !ok {
+		b = newBreaker(c.failureRatio, c.cooldown)
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// Do sends req - after running it through [tracker.Request] to propagate the GUID/trace
+// headers - retrying idempotent methods with exponential backoff and jitter (honoring a
+// Retry-After response header) while the destination host's circuit breaker allows it, and
+// recording every attempt via the configured [Recorder]. It aborts immediately once
+// context.Cause(req.Context()) reports a non-retryable cancellation.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	req = tracker.Request(req)
+	host := req.URL.Host
+	b := c.breakerFor(host)
+
+	maxAttempts := c.maxAttempts
+	if !idempotentMethods[req.Method] {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if cause := context.Cause(req.Context()); cause != nil {
+			return nil, cause
+		}
+		if !b.allow() {
+			return nil, fmt.Errorf("httpclient: circuit open for %s", host)
+		}
+
+		attemptReq := req
+		if attempt > 1 {
+			cloned, err := cloneWithBody(req)
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = cloned
+		}
+
+		start := time.Now()
+		resp, err := c.httpClient.Do(attemptReq)
+		elapsed := time.Since(start)
+
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		c.recorder.Record(host, attempt, elapsed, statusCode, err)
+
+		if err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			b.recordSuccess()
+			return resp, nil
+		}
+
+		b.recordFailure()
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("httpclient: unsuccessful response: %s", resp.Status)
+			resp.Body.Close()
+		}
+
+		if attempt == maxAttempts {
+			break
+		}
+
+		delay := backoff(c.baseDelay, c.maxDelay, attempt)
+		if resp != nil {
+			if ra := retryAfter(resp.Header.Get("Retry-After")); ra > 0 {
+				delay = ra
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			if cause := context.Cause(req.Context()); cause != nil {
+				return nil, cause
+			}
+			return nil, req.Context().Err()
+		}
+	}
+	return nil, lastErr
+}
+
+// cloneWithBody clones req for a retry, re-reading its body via GetBody so it can be sent
+// again safely - req.Body itself has already been drained by the previous attempt.
+func cloneWithBody(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody == nil {
+		return clone, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+// backoff returns an exponentially increasing delay for attempt (1-indexed), capped at max and
+// jittered by up to half its value so retrying callers don't all wake up in lockstep.
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	d := base * time.Duration(1<<uint(attempt-1))
+	if d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// retryAfter parses a Retry-After header, either a number of seconds or an HTTP-date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
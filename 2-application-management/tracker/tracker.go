@@ -4,13 +4,20 @@
 //
 // By using the dependency injection technique with implicit interfaces,
 // any business logic is completely unaware of any tracking information.
-// see [main.Logger] and [main.RequestDecorator]
+// see [GUIDFromContext], [TraceFromContext] and [Request]
 package tracker
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"serverkit"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -35,6 +42,99 @@ func guidFromContext(ctx context.Context) (string, bool) {
 	return g, ok
 }
 
+// GUIDFromContext exposes guidFromContext to other packages, e.g. logctx, that want to
+// attach the request's GUID to something of their own without reimplementing how it's read.
+func GUIDFromContext(ctx context.Context) (string, bool) {
+	return guidFromContext(ctx)
+}
+
+// traceKey represents an unexported key-type for writing and reading a [TraceContext] into a [context.Context]
+type traceKey int
+
+// tKey is an unexported constant of the key-type traceKey
+const tKey traceKey = 1
+
+const (
+	// traceparentHeader and tracestateHeader are the W3C trace-context headers, see
+	// https://www.w3.org/TR/trace-context/
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+	traceVersion      = "00"
+)
+
+// TraceContext carries the W3C trace-context fields that are propagated along a request chain
+// and across service boundaries.
+type TraceContext struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	TraceState   string
+	Sampled      bool
+}
+
+// traceparent formats tc as a W3C "traceparent" header value
+func (tc TraceContext) traceparent() string {
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("%s-%s-%s-%s", traceVersion, tc.TraceID, tc.SpanID, flags)
+}
+
+// newTraceID generates a random 16-byte trace-id, hex-encoded as required by the W3C spec
+func newTraceID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newSpanID generates a random 8-byte span-id, hex-encoded as required by the W3C spec
+func newSpanID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// parseTraceparent parses a W3C "traceparent" header value of the form
+// "version-trace_id-parent_id-flags" into a [TraceContext]
+//
+// the returned [TraceContext] already carries a freshly generated span-id for the current
+// service; [TraceContext.ParentSpanID] holds the span-id that was received on the wire
+func parseTraceparent(h string) (TraceContext, bool) {
+	parts := strings.Split(h, "-")
+	if len(parts) != 4 {
+		return TraceContext{}, false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if version != traceVersion || len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 {
+		return TraceContext{}, false
+	}
+	return TraceContext{
+		TraceID:      traceID,
+		SpanID:       newSpanID(),
+		ParentSpanID: spanID,
+		Sampled:      flags == "01",
+	}, true
+}
+
+// contextWithTrace is an API to add a [TraceContext] to a [context.Context]
+func contextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, tKey, tc)
+}
+
+// traceFromContext is an API to read a [TraceContext] from a [context.Context] instance
+func traceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(tKey).(TraceContext)
+	return tc, ok
+}
+
+// TraceFromContext exposes traceFromContext to other packages, e.g. logctx, that want to
+// attach the request's trace-id/span-id to something of their own without reimplementing how
+// it's read.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	return traceFromContext(ctx)
+}
+
 func Middleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
@@ -47,6 +147,14 @@ func Middleware(h http.Handler) http.Handler {
 			ctx = contextWithGUID(ctx, uuid.New().String())
 		}
 
+		// parse an incoming traceparent, or start a brand-new trace if this is the first service in the chain
+		tc, ok := parseTraceparent(req.Header.Get(traceparentHeader))
+		if !ok {
+			tc = TraceContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+		}
+		tc.TraceState = req.Header.Get(tracestateHeader)
+		ctx = contextWithTrace(ctx, tc)
+
 		// the old request and the enriched context are used to create a new request
 		req = req.WithContext(ctx)
 		h.ServeHTTP(rw, req)
@@ -57,25 +165,77 @@ type Logger struct{}
 
 // Log offers a generic logging method that takes in a [context.Context] and a string
 //
-// if there is a [uuid] in the context it appends it to the beginning of the log message
-// and outputs it
+// if there is a [uuid] and/or a [TraceContext] in the context, they are attached as
+// structured attributes so the log line correlates with the request chain and its spans
 func (Logger) Log(ctx context.Context, message string) {
+	var attrs []any
 	if guid, ok := guidFromContext(ctx); ok {
-		message = fmt.Sprintf("GUID: %s - %s", guid, message)
+		attrs = append(attrs, slog.String("guid", guid))
+	}
+	if tc, ok := traceFromContext(ctx); ok {
+		attrs = append(attrs, slog.String("trace_id", tc.TraceID), slog.String("span_id", tc.SpanID))
 	}
-	// do logging
-	fmt.Println(message)
+	slog.Default().Info(message, attrs...)
 }
 
 // Request is used when this service makes a call to another service
 //
-// it takes in an [*http.Request], adds the header with the [uuid]
-// if it exists in the [context.Context] instance, and
-// returns the [*http.Request]
+// it takes in an [*http.Request], adds the header with the [uuid] if it exists in the
+// [context.Context] instance, propagates the current trace by generating a new child span-id
+// while preserving the trace-id, writes out the remaining deadline budget (see
+// [serverkit.DeadlineBudget]) so the downstream service inherits a shrinking deadline rather
+// than starting a fresh one, and returns the [*http.Request]
 func Request(req *http.Request) *http.Request {
 	ctx := req.Context()
 	if guid, ok := guidFromContext(ctx); ok {
 		req.Header.Add("X-GUID", guid)
 	}
+	if tc, ok := traceFromContext(ctx); ok {
+		child := TraceContext{
+			TraceID:      tc.TraceID,
+			SpanID:       newSpanID(),
+			ParentSpanID: tc.SpanID,
+			Sampled:      tc.Sampled,
+		}
+		req.Header.Set(traceparentHeader, child.traceparent())
+		if tc.TraceState != "" {
+			req.Header.Set(tracestateHeader, tc.TraceState)
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(serverkit.DeadlineHeader, strconv.FormatInt(deadline.UnixNano(), 10))
+	}
 	return req
 }
+
+// EndFunc finishes a span started by [StartSpan]
+type EndFunc func()
+
+// StartSpan opens a child span named name from whatever trace is already in ctx (or starts a
+// brand-new one if ctx carries none), and returns a [context.Context] carrying the new span
+// along with an [EndFunc] that, once called, logs the span's name and duration.
+//
+// LogicImpl.Process uses this to bracket outbound HTTP calls:
+//
+//	ctx, end := tracker.StartSpan(ctx, "call-second")
+//	defer end()
+func StartSpan(ctx context.Context, name string) (context.Context, EndFunc) {
+	var tc TraceContext
+	if parent, ok := traceFromContext(ctx); ok {
+		tc = TraceContext{
+			TraceID:      parent.TraceID,
+			SpanID:       newSpanID(),
+			ParentSpanID: parent.SpanID,
+			TraceState:   parent.TraceState,
+			Sampled:      parent.Sampled,
+		}
+	} else {
+		tc = TraceContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	}
+	ctx = contextWithTrace(ctx, tc)
+
+	start := time.Now()
+	return ctx, func() {
+		Logger{}.Log(ctx, fmt.Sprintf("span %q finished in %s", name, time.Since(start)))
+	}
+}
@@ -0,0 +1,127 @@
+// Package logctx gives request handlers a pre-populated [*slog.Logger] through their
+// [context.Context] instead of threading one explicitly.
+//
+// it replaces two ad-hoc patterns that used to live next to each other: tracker.Logger, which
+// only ever knew how to prefix a message with a GUID, and the log-level-in-context exercise,
+// which only ever knew how to gate Debug/Info messages. [Middleware] builds one [*slog.Logger]
+// per request, carrying whatever attributes its [Extractor]s pull off the request plus a log
+// level read from the request itself, and stores it on the context for business logic to use
+// via [From].
+package logctx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// loggerKey is an unexported key-type for writing and reading a [*slog.Logger] into a [context.Context]
+type loggerKey struct{}
+
+// noop is returned by [From] when no logger has been attached to the context
+var noop = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// ContextWithLogger is an API to add a [*slog.Logger] to a [context.Context]
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// From returns the [*slog.Logger] attached to ctx by [Middleware], or a no-op logger if none was attached
+func From(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return noop
+}
+
+// WithAttrs returns a new context whose logger is [From](ctx) enriched with attrs, so a
+// handler can add request-specific detail (e.g. a resource ID) to every log line it emits
+// from that point on without passing a logger around explicitly.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	args := make([]any, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+	return ContextWithLogger(ctx, From(ctx).With(args...))
+}
+
+// Extractor pulls one piece of request-scoped metadata off req, e.g. a GUID from tracker or a
+// user from identity, returning ok=false when it has nothing to contribute. Keeping this as a
+// function value lets Middleware stay unaware of tracker/identity, the same dependency
+// injection technique tracker itself uses for [tracker.Logger] / [tracker.Request].
+type Extractor func(req *http.Request) (key, value string, ok bool)
+
+type middlewareConfig struct {
+	extractors []Extractor
+}
+
+// MiddlewareOption configures [Middleware]
+type MiddlewareOption func(*middlewareConfig)
+
+// WithExtractor adds an [Extractor] whose result is attached as an attribute to every log
+// line the request's logger emits
+func WithExtractor(e Extractor) MiddlewareOption {
+	return func(cfg *middlewareConfig) { cfg.extractors = append(cfg.extractors, e) }
+}
+
+// Middleware builds a per-request [*slog.Logger], pre-populated with the request's method,
+// path and remote address, whatever the configured [Extractor]s contribute, and a log level
+// read from the "log_level" query parameter or the "X-Log-Level" header (defaulting to info),
+// and attaches it to the request context for [From] to return.
+func Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	var cfg middlewareConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			level := new(slog.LevelVar)
+			level.Set(levelFromRequest(req))
+
+			handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+			logger := slog.New(handler).With(
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("remote_addr", remoteAddr(req)),
+			)
+			for _, extract := range cfg.extractors {
+				if k, v, ok := extract(req); ok {
+					logger = logger.With(slog.String(k, v))
+				}
+			}
+
+			ctx := ContextWithLogger(req.Context(), logger)
+			h.ServeHTTP(rw, req.WithContext(ctx))
+		})
+	}
+}
+
+// remoteAddr prefers the left-most address in X-Forwarded-For, the one closest to the
+// original client, falling back to req.RemoteAddr when the header is absent - the same
+// approach docker/distribution uses to recover the real client address behind a proxy.
+func remoteAddr(req *http.Request) string {
+	if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+		if addr := strings.TrimSpace(strings.Split(xff, ",")[0]); addr != "" {
+			return addr
+		}
+	}
+	return req.RemoteAddr
+}
+
+// levelFromRequest reads the desired log level from the "log_level" query parameter, falling
+// back to the "X-Log-Level" header, and defaults to info if neither is set or valid.
+func levelFromRequest(req *http.Request) slog.Level {
+	raw := req.URL.Query().Get("log_level")
+	if raw == "" {
+		raw = req.Header.Get("X-Log-Level")
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(raw)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
@@ -0,0 +1,137 @@
+// Package serverkit provides the process-level plumbing an [http.Server] needs that has
+// nothing to do with any one service's business logic: shutting down gracefully, actually
+// enforcing a per-request timeout, and letting a shrinking deadline budget follow a request
+// across service boundaries instead of every hop restarting its own clock.
+package serverkit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Run starts srv, blocks until ctx is cancelled or the process receives SIGINT/SIGTERM, and
+// then calls srv.Shutdown with grace as its deadline. It returns the first error encountered,
+// either from ListenAndServe (ignoring [http.ErrServerClosed]) or from Shutdown.
+func Run(ctx context.Context, srv *http.Server, grace time.Duration) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+			return
+		}
+		serveErr <- nil
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	return <-serveErr
+}
+
+// Timeout returns a middleware that derives a context with deadline d from the request and -
+// unlike an earlier version of this middleware, which built the context and then never used
+// it - installs it on the request via r.WithContext, so the downstream handler actually
+// observes the deadline. The handler runs in its own goroutine against a buffered
+// ResponseWriter so that, if the deadline passes first, Timeout can still win the race and
+// respond 504 Gateway Timeout instead of whatever the handler eventually writes.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			ctx, cancel := context.WithTimeout(req.Context(), d)
+			defer cancel()
+			req = req.WithContext(ctx)
+
+			buffered := newBufferedResponseWriter()
+			done := make(chan any, 1) // nil on a clean return, the recovered panic value otherwise
+			go func() {
+				defer func() { done <- recover() }()
+				h.ServeHTTP(buffered, req)
+			}()
+
+			select {
+			case v := <-done:
+				if v != nil {
+					// re-panic on this goroutine (the request's own) so it reaches the
+					// same recover the http.Server would have caught it with had
+					// Timeout not been in the chain
+					panic(v)
+				}
+				buffered.flushTo(rw)
+			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					rw.WriteHeader(http.StatusGatewayTimeout)
+				}
+			}
+		})
+	}
+}
+
+// bufferedResponseWriter lets [Timeout] capture a handler's response without letting it reach
+// the real [http.ResponseWriter] until the handler has actually won the race against the
+// deadline.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *bufferedResponseWriter) Header() http.Header { return w.header }
+
+func (w *bufferedResponseWriter) WriteHeader(status int) { w.status = status }
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+// flushTo replays the buffered response onto rw
+func (w *bufferedResponseWriter) flushTo(rw http.ResponseWriter) {
+	for k, v := range w.header {
+		rw.Header()[k] = v
+	}
+	rw.WriteHeader(w.status)
+	rw.Write(w.body.Bytes())
+}
+
+// DeadlineHeader carries a request's remaining deadline budget as a unix-nanosecond timestamp
+const DeadlineHeader = "X-Request-Deadline"
+
+// DeadlineBudget reads an incoming [DeadlineHeader] and, if present and parseable, applies it
+// to the request's context via context.WithDeadline - so a deadline set by the first service
+// in a chain keeps shrinking as the request is handed off, rather than every downstream
+// service starting a fresh timeout of its own. A deadline that has already passed is applied
+// as well, so the request's context is done from the start.
+func DeadlineBudget(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		if raw := req.Header.Get(DeadlineHeader); raw != "" {
+			if nanos, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, time.Unix(0, nanos))
+				defer cancel()
+			}
+		}
+
+		h.ServeHTTP(rw, req.WithContext(ctx))
+	})
+}
@@ -0,0 +1,128 @@
+package serverkit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimeout_WritesGatewayTimeoutWhenHandlerIsStillRunning(t *testing.T) {
+	handlerDone := make(chan struct{})
+	h := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		// an ill-behaved handler that keeps going past the deadline and tries to write its
+		// own response - Timeout must not let this reach the client
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte("too slow"))
+		close(handlerDone)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+
+	select {
+	case <-handlerDone:
+	case <-time.After(time.Second):
+		t.Fatal("handler goroutine never finished")
+	}
+}
+
+func TestTimeout_HandlerFinishingInTimePassesThroughUnchanged(t *testing.T) {
+	h := Timeout(time.Second)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+		rw.Write([]byte("ok"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTeapot)
+	}
+	if rec.Body.String() != "ok" {
+		t.Fatalf("body = %q, want %q", rec.Body.String(), "ok")
+	}
+}
+
+func TestTimeout_HandlerPanicPropagatesToCaller(t *testing.T) {
+	h := Timeout(time.Second)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected Timeout to re-panic the handler's panic")
+		}
+	}()
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Fatal("unreachable: ServeHTTP should have panicked")
+}
+
+// freeAddr finds a free local port by briefly binding to it, so Run can be handed a concrete
+// address without racing to discover one after ListenAndServe has already started.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestRun_ShutsDownGracefullyAfterHandlerPanics(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		panic("boom")
+	})
+
+	addr := freeAddr(t)
+	srv := &http.Server{Addr: addr, Handler: mux}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- Run(ctx, srv, 2*time.Second) }()
+
+	// net/http recovers a handler panic by logging it and closing the connection without
+	// writing a response, so the client sees a connection error rather than a status code;
+	// what this test cares about is that the panic didn't crash the whole process, and that
+	// Run still shuts down cleanly afterwards.
+	var reached bool
+	for i := 0; i < 50; i++ {
+		resp, err := http.Get("http://" + addr + "/panic")
+		if err == nil {
+			resp.Body.Close()
+		}
+		if atomic.LoadInt32(&requests) > 0 {
+			reached = true
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !reached {
+		t.Fatal("handler was never reached")
+	}
+
+	cancel()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run returned %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after shutdown was triggered")
+	}
+}